@@ -0,0 +1,373 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	kapi "github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	kerrors "github.com/GoogleCloudPlatform/kubernetes/pkg/api/errors"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/runtime"
+
+	deployapi "github.com/openshift/origin/pkg/deploy/api"
+	deployutil "github.com/openshift/origin/pkg/deploy/util"
+	imageapi "github.com/openshift/origin/pkg/image/api"
+)
+
+// Client abstracts access to the underlying storage the generator needs in
+// order to produce a new DeploymentConfig. Each field is a narrow function
+// hook so callers (and tests) can stub out exactly the access pattern they
+// need without depending on a full set of REST storage implementations.
+type Client struct {
+	DCFn  func(ctx kapi.Context, name string) (*deployapi.DeploymentConfig, error)
+	LISFn func(ctx kapi.Context) (*imageapi.ImageStreamList, error)
+	ISFn  func(ctx kapi.Context, name string) (*imageapi.ImageStream, error)
+	// RCFn looks up the ReplicationController backing a previous deployment,
+	// by name, so its embedded DeploymentConfig can be recovered for a
+	// rollback.
+	RCFn func(ctx kapi.Context, name string) (*kapi.ReplicationController, error)
+	// GRFn generates the rollback of to onto from, honoring spec.
+	GRFn func(from, to *deployapi.DeploymentConfig, spec *deployapi.DeploymentConfigRollbackSpec) (*deployapi.DeploymentConfig, error)
+}
+
+// DeploymentConfigGenerator generates new DeploymentConfigs by incorporating
+// the latest state of triggers which affect an existing DeploymentConfig.
+type DeploymentConfigGenerator struct {
+	Codec  runtime.Codec
+	Client Client
+}
+
+// Generate returns a DeploymentConfig with its LatestVersion incremented if
+// any of its image change triggers are satisfied by images that are newer
+// than the ones currently referenced in the pod template, or if its config
+// change trigger (if any) finds the pod template has diverged from the most
+// recent deployment. When force is true, every image change trigger is
+// resolved and applied unconditionally and LatestVersion is always
+// incremented, regardless of whether anything actually changed; an image
+// trigger that can't be resolved is an error rather than a silent no-op.
+func (g *DeploymentConfigGenerator) Generate(ctx kapi.Context, name string, force bool) (*deployapi.DeploymentConfig, error) {
+	config, err := g.Client.DCFn(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := false
+	var failures []TriggerResolutionFailure
+
+	for _, trigger := range config.Triggers {
+		switch trigger.Type {
+		case deployapi.DeploymentTriggerOnImageChange:
+			params := trigger.ImageChangeParams
+
+			stream, err := g.findImageStream(ctx, params)
+			if err != nil {
+				return nil, err
+			}
+			if stream == nil {
+				if force {
+					return nil, fmt.Errorf("could not resolve image stream for trigger on containers %v", params.ContainerNames)
+				}
+				continue
+			}
+
+			if len(stream.Status.DockerImageRepository) == 0 {
+				return nil, fmt.Errorf("image stream %s/%s does not have a Docker image repository", stream.Namespace, stream.Name)
+			}
+
+			newImage, ok := resolveTagReference(stream, params.Tag)
+			if !ok {
+				if force {
+					return nil, fmt.Errorf("could not resolve tag %q on image stream %s/%s", params.Tag, kapi.NamespaceValue(ctx), stream.Name)
+				}
+				for _, containerName := range params.ContainerNames {
+					failures = append(failures, TriggerResolutionFailure{
+						ContainerName:  containerName,
+						RepositoryName: stream.Status.DockerImageRepository,
+						Tag:            params.Tag,
+					})
+				}
+				continue
+			}
+
+			if force {
+				g.setContainerImages(config, params.ContainerNames, newImage)
+				changed = true
+			} else if g.updateContainerImages(config, params.ContainerNames, newImage) {
+				changed = true
+			}
+
+		case deployapi.DeploymentTriggerOnConfigChange:
+			configChanged, err := g.podTemplateChangedSinceLastDeployment(ctx, config)
+			if err != nil {
+				return nil, err
+			}
+			if configChanged {
+				changed = true
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		return config, &TriggerResolutionError{Failures: failures}
+	}
+
+	if changed || force || config.LatestVersion == 0 {
+		config.LatestVersion++
+	}
+
+	return config, nil
+}
+
+// TriggerResolutionFailure describes a single image change trigger whose tag
+// could not be resolved against its image stream.
+type TriggerResolutionFailure struct {
+	ContainerName  string
+	RepositoryName string
+	Tag            string
+}
+
+// TriggerResolutionError is returned by Generate, when force is false, if one
+// or more image change triggers reference a tag that isn't registered on their
+// image stream. It aggregates every such failure from a single Generate call so
+// they can all be reported at once; the REST layer surfaces it as a 422.
+// Callers that want the config Generate was still able to produce can
+// type-assert the error and use the returned config for partial success.
+type TriggerResolutionError struct {
+	Failures []TriggerResolutionFailure
+}
+
+func (e *TriggerResolutionError) Error() string {
+	descriptions := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		descriptions[i] = fmt.Sprintf("%s: tag %q is not registered on %q", f.ContainerName, f.Tag, f.RepositoryName)
+	}
+	return fmt.Sprintf("unable to resolve image trigger(s): %s", strings.Join(descriptions, "; "))
+}
+
+// podTemplateChangedSinceLastDeployment reports whether config's pod template
+// differs from the one embedded in the most recent deployment for config. A
+// config with no prior deployment is treated as changed.
+func (g *DeploymentConfigGenerator) podTemplateChangedSinceLastDeployment(ctx kapi.Context, config *deployapi.DeploymentConfig) (bool, error) {
+	rc, err := g.Client.RCFn(ctx, deployutil.LatestDeploymentNameForConfig(config))
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	previous, err := deployutil.DecodeDeploymentConfig(rc, g.Codec)
+	if err != nil {
+		return false, err
+	}
+
+	return !kapi.Semantic.DeepEqual(previous.Template.ControllerTemplate.Template, config.Template.ControllerTemplate.Template), nil
+}
+
+// findImageStream resolves the ImageStream referenced by an image change
+// trigger's params, preferring the From reference (looked up by name within
+// the DeploymentConfig's namespace) and falling back to the legacy
+// RepositoryName lookup against the full list.
+func (g *DeploymentConfigGenerator) findImageStream(ctx kapi.Context, params *deployapi.DeploymentTriggerImageChangeParams) (*imageapi.ImageStream, error) {
+	if len(params.From.Name) > 0 {
+		return g.Client.ISFn(ctx, params.From.Name)
+	}
+
+	streams, err := g.Client.LISFn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range streams.Items {
+		if streams.Items[i].Status.DockerImageRepository == params.RepositoryName {
+			return &streams.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// resolveTagReference returns the pull spec a tag currently resolves to,
+// preferring the stream's recorded tag history and falling back to the tag's
+// spec declaration when no history has been recorded yet.
+func resolveTagReference(stream *imageapi.ImageStream, tag string) (string, bool) {
+	if events, ok := stream.Status.Tags[tag]; ok && len(events.Items) > 0 {
+		return events.Items[0].DockerImageReference, true
+	}
+	if _, ok := stream.Spec.Tags[tag]; ok {
+		return fmt.Sprintf("%s:%s", stream.Status.DockerImageRepository, tag), true
+	}
+	return "", false
+}
+
+// NewLegacyImageRepositoryClient builds a Client whose LISFn/ISFn are backed
+// by legacy ImageRepository hooks, translating each response through
+// ImageRepositoryToImageStream. It lets callers that haven't migrated off
+// ImageRepository keep using DeploymentConfigGenerator during the transition
+// to ImageStream.
+func NewLegacyImageRepositoryClient(lirFn func(ctx kapi.Context) (*imageapi.ImageRepositoryList, error), irFn func(ctx kapi.Context, name string) (*imageapi.ImageRepository, error)) Client {
+	return Client{
+		LISFn: func(ctx kapi.Context) (*imageapi.ImageStreamList, error) {
+			repos, err := lirFn(ctx)
+			if err != nil {
+				return nil, err
+			}
+			streams := &imageapi.ImageStreamList{Items: make([]imageapi.ImageStream, len(repos.Items))}
+			for i := range repos.Items {
+				streams.Items[i] = *ImageRepositoryToImageStream(&repos.Items[i])
+			}
+			return streams, nil
+		},
+		ISFn: func(ctx kapi.Context, name string) (*imageapi.ImageStream, error) {
+			repo, err := irFn(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			return ImageRepositoryToImageStream(repo), nil
+		},
+	}
+}
+
+// ImageRepositoryToImageStream adapts a legacy ImageRepository into the
+// equivalent ImageStream shape. It exists so callers still producing
+// ImageRepository responses (via LIRFn/IRFn-style hooks) can be wired into
+// Client's LISFn/ISFn during the transition to ImageStream without forking
+// the generator's resolution logic.
+func ImageRepositoryToImageStream(repo *imageapi.ImageRepository) *imageapi.ImageStream {
+	repoName := repo.Status.DockerImageRepository
+	if len(repoName) == 0 {
+		repoName = repo.DockerImageRepository
+	}
+
+	stream := &imageapi.ImageStream{
+		ObjectMeta: repo.ObjectMeta,
+		Status: imageapi.ImageStreamStatus{
+			DockerImageRepository: repoName,
+			Tags:                  map[string]imageapi.TagEventList{},
+		},
+	}
+	for tag, ref := range repo.Tags {
+		stream.Status.Tags[tag] = imageapi.TagEventList{
+			Items: []imageapi.TagEvent{
+				{DockerImageReference: fmt.Sprintf("%s:%s", repoName, ref)},
+			},
+		}
+	}
+	return stream
+}
+
+// updateContainerImages rewrites the image of every named container in the
+// deployment config's pod template to newImage, returning true if anything
+// changed.
+func (g *DeploymentConfigGenerator) updateContainerImages(config *deployapi.DeploymentConfig, containerNames []string, newImage string) bool {
+	changed := false
+	containers := config.Template.ControllerTemplate.Template.Spec.Containers
+	for _, name := range containerNames {
+		for i := range containers {
+			if containers[i].Name == name && containers[i].Image != newImage {
+				containers[i].Image = newImage
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// setContainerImages unconditionally rewrites the image of every named
+// container in the deployment config's pod template to newImage, used by
+// forced generation where the image is reapplied even if it already matches.
+func (g *DeploymentConfigGenerator) setContainerImages(config *deployapi.DeploymentConfig, containerNames []string, newImage string) {
+	containers := config.Template.ControllerTemplate.Template.Spec.Containers
+	for _, name := range containerNames {
+		for i := range containers {
+			if containers[i].Name == name {
+				containers[i].Image = newImage
+			}
+		}
+	}
+}
+
+// GenerateRollback looks up the ReplicationController named from, decodes its
+// embedded DeploymentConfig, and uses it to produce the rollback of to per
+// spec.
+func (g *DeploymentConfigGenerator) GenerateRollback(ctx kapi.Context, from string, to *deployapi.DeploymentConfig, spec *deployapi.DeploymentConfigRollbackSpec) (*deployapi.DeploymentConfig, error) {
+	rc, err := g.Client.RCFn(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+
+	fromConfig, err := deployutil.DecodeDeploymentConfig(rc, g.Codec)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.Client.GRFn(fromConfig, to, spec)
+}
+
+// DeploymentConfigRollbackGenerator generates a new DeploymentConfig by
+// merging the fields enabled by a DeploymentConfigRollbackSpec from an older
+// config into the current one.
+type DeploymentConfigRollbackGenerator struct{}
+
+// GenerateRollback merges the sections of from enabled by spec into a copy
+// of to and returns the result with LatestVersion bumped so a new deployment
+// can be created from it.
+func (g *DeploymentConfigRollbackGenerator) GenerateRollback(from, to *deployapi.DeploymentConfig, spec *deployapi.DeploymentConfigRollbackSpec) (*deployapi.DeploymentConfig, error) {
+	rolledback := *to
+	rolledback.Triggers = copyTriggers(to.Triggers)
+	rolledback.Template.ControllerTemplate.Template = copyPodTemplate(to.Template.ControllerTemplate.Template)
+	rolledback.Template.ControllerTemplate.Selector = copySelector(to.Template.ControllerTemplate.Selector)
+
+	if spec.IncludeTriggers {
+		rolledback.Triggers = copyTriggers(from.Triggers)
+	}
+
+	if spec.IncludeTemplate {
+		rolledback.Template.ControllerTemplate.Template = copyPodTemplate(from.Template.ControllerTemplate.Template)
+	}
+
+	if spec.IncludeReplicationMeta {
+		rolledback.Template.ControllerTemplate.Replicas = from.Template.ControllerTemplate.Replicas
+		rolledback.Template.ControllerTemplate.Selector = copySelector(from.Template.ControllerTemplate.Selector)
+	}
+
+	if spec.IncludeStrategy {
+		rolledback.Template.Strategy = from.Template.Strategy
+	}
+
+	rolledback.LatestVersion = to.LatestVersion + 1
+
+	return &rolledback, nil
+}
+
+// copyTriggers returns an independent copy of triggers so the rolled back
+// config doesn't alias the slice owned by whichever source config (from or
+// to) it was merged from.
+func copyTriggers(triggers []deployapi.DeploymentTriggerPolicy) []deployapi.DeploymentTriggerPolicy {
+	copied := make([]deployapi.DeploymentTriggerPolicy, len(triggers))
+	copy(copied, triggers)
+	return copied
+}
+
+// copyPodTemplate returns an independent copy of template, including its
+// container slice, so the rolled back config doesn't alias the pod template
+// pointer owned by whichever source config it was merged from.
+func copyPodTemplate(template *kapi.PodTemplateSpec) *kapi.PodTemplateSpec {
+	if template == nil {
+		return nil
+	}
+	copied := *template
+	copied.Spec.Containers = append([]kapi.Container{}, template.Spec.Containers...)
+	return &copied
+}
+
+// copySelector returns an independent copy of selector so the rolled back
+// config doesn't alias the map owned by whichever source config it was
+// merged from.
+func copySelector(selector map[string]string) map[string]string {
+	if selector == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(selector))
+	for k, v := range selector {
+		copied[k] = v
+	}
+	return copied
+}
@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
@@ -27,7 +28,7 @@ func TestGenerateFromMissingDeploymentConfig(t *testing.T) {
 		},
 	}
 
-	config, err := generator.Generate(kapi.NewDefaultContext(), "1234")
+	config, err := generator.Generate(kapi.NewDefaultContext(), "1234", false)
 
 	if config != nil {
 		t.Fatalf("Unexpected deployment config generated: %#v", config)
@@ -45,13 +46,13 @@ func TestGenerateFromConfigWithoutTagChange(t *testing.T) {
 			DCFn: func(ctx kapi.Context, id string) (*deployapi.DeploymentConfig, error) {
 				return deploytest.OkDeploymentConfig(1), nil
 			},
-			LIRFn: func(ctx kapi.Context) (*imageapi.ImageRepositoryList, error) {
-				return okImageRepoList(), nil
+			LISFn: func(ctx kapi.Context) (*imageapi.ImageStreamList, error) {
+				return okImageStreamList(), nil
 			},
 		},
 	}
 
-	config, err := generator.Generate(kapi.NewDefaultContext(), "deploy1")
+	config, err := generator.Generate(kapi.NewDefaultContext(), "deploy1", false)
 
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
@@ -75,13 +76,13 @@ func TestGenerateFromZeroConfigWithoutTagChange(t *testing.T) {
 			DCFn: func(ctx kapi.Context, id string) (*deployapi.DeploymentConfig, error) {
 				return dc, nil
 			},
-			LIRFn: func(ctx kapi.Context) (*imageapi.ImageRepositoryList, error) {
-				return okImageRepoList(), nil
+			LISFn: func(ctx kapi.Context) (*imageapi.ImageStreamList, error) {
+				return okImageStreamList(), nil
 			},
 		},
 	}
 
-	config, err := generator.Generate(kapi.NewDefaultContext(), "deploy1")
+	config, err := generator.Generate(kapi.NewDefaultContext(), "deploy1", false)
 
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
@@ -103,13 +104,13 @@ func TestGenerateFromConfigWithNoDeployment(t *testing.T) {
 			DCFn: func(ctx kapi.Context, id string) (*deployapi.DeploymentConfig, error) {
 				return deploytest.OkDeploymentConfig(1), nil
 			},
-			LIRFn: func(ctx kapi.Context) (*imageapi.ImageRepositoryList, error) {
-				return okImageRepoList(), nil
+			LISFn: func(ctx kapi.Context) (*imageapi.ImageStreamList, error) {
+				return okImageStreamList(), nil
 			},
 		},
 	}
 
-	config, err := generator.Generate(kapi.NewDefaultContext(), "deploy2")
+	config, err := generator.Generate(kapi.NewDefaultContext(), "deploy2", false)
 
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
@@ -131,15 +132,19 @@ func TestGenerateFromConfigWithUpdatedImageRef(t *testing.T) {
 			DCFn: func(ctx kapi.Context, id string) (*deployapi.DeploymentConfig, error) {
 				return deploytest.OkDeploymentConfig(1), nil
 			},
-			LIRFn: func(ctx kapi.Context) (*imageapi.ImageRepositoryList, error) {
-				list := okImageRepoList()
-				list.Items[0].Tags["tag1"] = "ref2"
+			LISFn: func(ctx kapi.Context) (*imageapi.ImageStreamList, error) {
+				list := okImageStreamList()
+				list.Items[0].Status.Tags["tag1"] = imageapi.TagEventList{
+					Items: []imageapi.TagEvent{
+						{DockerImageReference: "registry:8080/repo1:ref2"},
+					},
+				}
 				return list, nil
 			},
 		},
 	}
 
-	config, err := generator.Generate(kapi.NewDefaultContext(), "deploy1")
+	config, err := generator.Generate(kapi.NewDefaultContext(), "deploy1", false)
 
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
@@ -167,20 +172,128 @@ func TestGenerateReportsErrorWhenRepoHasNoImage(t *testing.T) {
 			DCFn: func(ctx kapi.Context, name string) (*deployapi.DeploymentConfig, error) {
 				return referenceDeploymentConfig(), nil
 			},
-			IRFn: func(ctx kapi.Context, name string) (*imageapi.ImageRepository, error) {
-				return &emptyImageRepo().Items[0], nil
+			ISFn: func(ctx kapi.Context, name string) (*imageapi.ImageStream, error) {
+				return &emptyImageStream().Items[0], nil
 			},
 		},
 	}
-	_, err := generator.Generate(kapi.NewDefaultContext(), "deploy1")
+	_, err := generator.Generate(kapi.NewDefaultContext(), "deploy1", false)
 	if err == nil {
 		t.Fatalf("Unexpected non-error")
 	}
-	if !strings.Contains(err.Error(), "image repository /imageRepo1 does not have a Docker") {
+	if !strings.Contains(err.Error(), "image stream /imageRepo1 does not have a Docker") {
 		t.Errorf("unexpected error message: %v", err)
 	}
 }
 
+func TestGenerateReportsErrorWhenTagIsNotRegistered(t *testing.T) {
+	generator := &DeploymentConfigGenerator{
+		Codec: api.Codec,
+		Client: Client{
+			DCFn: func(ctx kapi.Context, name string) (*deployapi.DeploymentConfig, error) {
+				return referenceDeploymentConfig(), nil
+			},
+			ISFn: func(ctx kapi.Context, name string) (*imageapi.ImageStream, error) {
+				stream := internalImageStream().Items[0]
+				delete(stream.Status.Tags, "tag1")
+				return &stream, nil
+			},
+		},
+	}
+
+	config, err := generator.Generate(kapi.NewDefaultContext(), "deploy1", false)
+
+	if err == nil {
+		t.Fatalf("Expected a TriggerResolutionError")
+	}
+	resolutionErr, ok := err.(*TriggerResolutionError)
+	if !ok {
+		t.Fatalf("Expected a *TriggerResolutionError, got %T: %v", err, err)
+	}
+	if len(resolutionErr.Failures) != 1 {
+		t.Fatalf("Expected 1 failure, got %#v", resolutionErr.Failures)
+	}
+	failure := resolutionErr.Failures[0]
+	if failure.ContainerName != "container1" || failure.RepositoryName != "internal/namespace/imageRepo1" || failure.Tag != "tag1" {
+		t.Errorf("Unexpected failure: %#v", failure)
+	}
+	if config == nil {
+		t.Fatalf("Expected a partial config to still be returned")
+	}
+}
+
+func TestGenerateReportsErrorWhenSomeTriggersResolveAndSomeDoNot(t *testing.T) {
+	dc := referenceDeploymentConfig()
+	dc.Triggers = append(dc.Triggers, deployapi.DeploymentTriggerPolicy{
+		Type: deployapi.DeploymentTriggerOnImageChange,
+		ImageChangeParams: &deployapi.DeploymentTriggerImageChangeParams{
+			ContainerNames: []string{"container2"},
+			From:           kapi.ObjectReference{Name: "repo2"},
+			Tag:            "tag2",
+		},
+	})
+
+	generator := &DeploymentConfigGenerator{
+		Codec: api.Codec,
+		Client: Client{
+			DCFn: func(ctx kapi.Context, name string) (*deployapi.DeploymentConfig, error) {
+				return dc, nil
+			},
+			ISFn: func(ctx kapi.Context, name string) (*imageapi.ImageStream, error) {
+				switch name {
+				case "repo1":
+					return &internalImageStream().Items[0], nil
+				case "repo2":
+					return &imageapi.ImageStream{
+						ObjectMeta: kapi.ObjectMeta{Name: "imageRepo2"},
+						Status: imageapi.ImageStreamStatus{
+							DockerImageRepository: "internal/namespace/imageRepo2",
+							Tags: map[string]imageapi.TagEventList{
+								"tag1": {
+									Items: []imageapi.TagEvent{
+										{DockerImageReference: "internal/namespace/imageRepo2:ref1"},
+									},
+								},
+							},
+						},
+					}, nil
+				}
+				return nil, fmt.Errorf("unexpected stream name %s", name)
+			},
+		},
+	}
+
+	_, err := generator.Generate(kapi.NewDefaultContext(), "deploy1", false)
+
+	resolutionErr, ok := err.(*TriggerResolutionError)
+	if !ok {
+		t.Fatalf("Expected a *TriggerResolutionError, got %T: %v", err, err)
+	}
+	if len(resolutionErr.Failures) != 1 {
+		t.Fatalf("Expected 1 failure (the resolvable trigger should not fail), got %#v", resolutionErr.Failures)
+	}
+	if resolutionErr.Failures[0].ContainerName != "container2" {
+		t.Errorf("Expected the failure to be for container2, got %#v", resolutionErr.Failures[0])
+	}
+}
+
+func TestTriggerResolutionErrorString(t *testing.T) {
+	err := &TriggerResolutionError{
+		Failures: []TriggerResolutionFailure{
+			{ContainerName: "container1", RepositoryName: "registry:8080/repo1", Tag: "tag1"},
+			{ContainerName: "container2", RepositoryName: "registry:8080/repo2", Tag: "tag2"},
+		},
+	}
+
+	msg := err.Error()
+	if !strings.Contains(msg, `container1: tag "tag1" is not registered on "registry:8080/repo1"`) {
+		t.Errorf("unexpected error message: %s", msg)
+	}
+	if !strings.Contains(msg, `container2: tag "tag2" is not registered on "registry:8080/repo2"`) {
+		t.Errorf("unexpected error message: %s", msg)
+	}
+}
+
 func TestGenerateDeploymentConfigWithFrom(t *testing.T) {
 	generator := &DeploymentConfigGenerator{
 		Codec: api.Codec,
@@ -188,13 +301,13 @@ func TestGenerateDeploymentConfigWithFrom(t *testing.T) {
 			DCFn: func(ctx kapi.Context, name string) (*deployapi.DeploymentConfig, error) {
 				return referenceDeploymentConfig(), nil
 			},
-			IRFn: func(ctx kapi.Context, name string) (*imageapi.ImageRepository, error) {
-				return &internalImageRepo().Items[0], nil
+			ISFn: func(ctx kapi.Context, name string) (*imageapi.ImageStream, error) {
+				return &internalImageStream().Items[0], nil
 			},
 		},
 	}
 
-	config, err := generator.Generate(kapi.NewDefaultContext(), "deploy1")
+	config, err := generator.Generate(kapi.NewDefaultContext(), "deploy1", false)
 
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
@@ -215,17 +328,69 @@ func TestGenerateDeploymentConfigWithFrom(t *testing.T) {
 	}
 }
 
-func okImageRepoList() *imageapi.ImageRepositoryList {
+func TestGenerateWithLegacyImageRepositoryClient(t *testing.T) {
+	generator := &DeploymentConfigGenerator{
+		Codec: api.Codec,
+		Client: NewLegacyImageRepositoryClient(
+			func(ctx kapi.Context) (*imageapi.ImageRepositoryList, error) {
+				return nil, fmt.Errorf("unexpected call to LIRFn for a From-style trigger")
+			},
+			func(ctx kapi.Context, name string) (*imageapi.ImageRepository, error) {
+				return &internalImageRepo().Items[0], nil
+			},
+		),
+	}
+	generator.Client.DCFn = func(ctx kapi.Context, name string) (*deployapi.DeploymentConfig, error) {
+		return referenceDeploymentConfig(), nil
+	}
+
+	config, err := generator.Generate(kapi.NewDefaultContext(), "deploy1", false)
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if config.LatestVersion != 2 {
+		t.Fatalf("Expected config LatestVersion=2, got %d", config.LatestVersion)
+	}
+
+	expected := "internal/namespace/imageRepo1:ref1"
+	actual := config.Template.ControllerTemplate.Template.Spec.Containers[0].Image
+	if expected != actual {
+		t.Fatalf("Expected container image %s, got %s", expected, actual)
+	}
+}
+
+func internalImageRepo() *imageapi.ImageRepositoryList {
 	return &imageapi.ImageRepositoryList{
 		Items: []imageapi.ImageRepository{
 			{
-				ObjectMeta:            kapi.ObjectMeta{Name: "imageRepo1"},
-				DockerImageRepository: "registry:8080/repo1",
+				ObjectMeta: kapi.ObjectMeta{Name: "imageRepo1"},
 				Tags: map[string]string{
 					"tag1": "ref1",
 				},
 				Status: imageapi.ImageRepositoryStatus{
+					DockerImageRepository: "internal/namespace/imageRepo1",
+				},
+			},
+		},
+	}
+}
+
+func okImageStreamList() *imageapi.ImageStreamList {
+	return &imageapi.ImageStreamList{
+		Items: []imageapi.ImageStream{
+			{
+				ObjectMeta: kapi.ObjectMeta{Name: "imageRepo1"},
+				Status: imageapi.ImageStreamStatus{
 					DockerImageRepository: "registry:8080/repo1",
+					Tags: map[string]imageapi.TagEventList{
+						"tag1": {
+							Items: []imageapi.TagEvent{
+								{DockerImageReference: "registry:8080/repo1:ref1"},
+							},
+						},
+					},
 				},
 			},
 		},
@@ -323,31 +488,422 @@ func basicDeployment() *kapi.ReplicationController {
 	}
 }
 
-func internalImageRepo() *imageapi.ImageRepositoryList {
-	return &imageapi.ImageRepositoryList{
-		Items: []imageapi.ImageRepository{
+func internalImageStream() *imageapi.ImageStreamList {
+	return &imageapi.ImageStreamList{
+		Items: []imageapi.ImageStream{
 			{
 				ObjectMeta: kapi.ObjectMeta{Name: "imageRepo1"},
-				Tags: map[string]string{
-					"tag1": "ref1",
-				},
-				Status: imageapi.ImageRepositoryStatus{
+				Status: imageapi.ImageStreamStatus{
 					DockerImageRepository: "internal/namespace/imageRepo1",
+					Tags: map[string]imageapi.TagEventList{
+						"tag1": {
+							Items: []imageapi.TagEvent{
+								{DockerImageReference: "internal/namespace/imageRepo1:ref1"},
+							},
+						},
+					},
 				},
 			},
 		},
 	}
 }
 
-func emptyImageRepo() *imageapi.ImageRepositoryList {
-	return &imageapi.ImageRepositoryList{
-		Items: []imageapi.ImageRepository{
+func TestGenerateFromConfigChangeWithoutTemplateDiff(t *testing.T) {
+	dc := configChangeDeploymentConfig()
+	generator := &DeploymentConfigGenerator{
+		Codec: api.Codec,
+		Client: Client{
+			DCFn: func(ctx kapi.Context, name string) (*deployapi.DeploymentConfig, error) {
+				return dc, nil
+			},
+			RCFn: func(ctx kapi.Context, name string) (*kapi.ReplicationController, error) {
+				return deploymentFor(configChangeDeploymentConfig()), nil
+			},
+		},
+	}
+
+	config, err := generator.Generate(kapi.NewDefaultContext(), "deploy1", false)
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.LatestVersion != 1 {
+		t.Fatalf("Expected config LatestVersion=1, got %d", config.LatestVersion)
+	}
+}
+
+func TestGenerateFromConfigChangeWithTemplateDiff(t *testing.T) {
+	generator := &DeploymentConfigGenerator{
+		Codec: api.Codec,
+		Client: Client{
+			DCFn: func(ctx kapi.Context, name string) (*deployapi.DeploymentConfig, error) {
+				return configChangeDeploymentConfig(), nil
+			},
+			RCFn: func(ctx kapi.Context, name string) (*kapi.ReplicationController, error) {
+				previous := configChangeDeploymentConfig()
+				previous.Template.ControllerTemplate.Template.Spec.Containers[0].Image = "registry:8080/repo1:old"
+				return deploymentFor(previous), nil
+			},
+		},
+	}
+
+	config, err := generator.Generate(kapi.NewDefaultContext(), "deploy1", false)
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.LatestVersion != 2 {
+		t.Fatalf("Expected config LatestVersion=2, got %d", config.LatestVersion)
+	}
+}
+
+func TestGenerateFromConfigChangeWithNoPriorDeployment(t *testing.T) {
+	generator := &DeploymentConfigGenerator{
+		Codec: api.Codec,
+		Client: Client{
+			DCFn: func(ctx kapi.Context, name string) (*deployapi.DeploymentConfig, error) {
+				return configChangeDeploymentConfig(), nil
+			},
+			RCFn: func(ctx kapi.Context, name string) (*kapi.ReplicationController, error) {
+				return nil, kerrors.NewNotFound("replicationController", name)
+			},
+		},
+	}
+
+	config, err := generator.Generate(kapi.NewDefaultContext(), "deploy1", false)
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.LatestVersion != 2 {
+		t.Fatalf("Expected config LatestVersion=2, got %d", config.LatestVersion)
+	}
+}
+
+func TestGenerateFromConfigAndImageChangeTogether(t *testing.T) {
+	dc := configChangeDeploymentConfig()
+	dc.Triggers = append(dc.Triggers, deployapi.DeploymentTriggerPolicy{
+		Type: deployapi.DeploymentTriggerOnImageChange,
+		ImageChangeParams: &deployapi.DeploymentTriggerImageChangeParams{
+			ContainerNames: []string{"container1"},
+			RepositoryName: "registry:8080/repo1",
+			Tag:            "tag1",
+		},
+	})
+
+	generator := &DeploymentConfigGenerator{
+		Codec: api.Codec,
+		Client: Client{
+			DCFn: func(ctx kapi.Context, name string) (*deployapi.DeploymentConfig, error) {
+				return dc, nil
+			},
+			RCFn: func(ctx kapi.Context, name string) (*kapi.ReplicationController, error) {
+				return deploymentFor(configChangeDeploymentConfig()), nil
+			},
+			LISFn: func(ctx kapi.Context) (*imageapi.ImageStreamList, error) {
+				list := okImageStreamList()
+				list.Items[0].Status.Tags["tag1"] = imageapi.TagEventList{
+					Items: []imageapi.TagEvent{
+						{DockerImageReference: "registry:8080/repo1:ref2"},
+					},
+				}
+				return list, nil
+			},
+		},
+	}
+
+	config, err := generator.Generate(kapi.NewDefaultContext(), "deploy1", false)
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.LatestVersion != 2 {
+		t.Fatalf("Expected config LatestVersion=2, got %d", config.LatestVersion)
+	}
+}
+
+func TestGenerateForceWithUnchangedRepoStillBumps(t *testing.T) {
+	generator := &DeploymentConfigGenerator{
+		Codec: api.Codec,
+		Client: Client{
+			DCFn: func(ctx kapi.Context, id string) (*deployapi.DeploymentConfig, error) {
+				return deploytest.OkDeploymentConfig(1), nil
+			},
+			LISFn: func(ctx kapi.Context) (*imageapi.ImageStreamList, error) {
+				return okImageStreamList(), nil
+			},
+		},
+	}
+
+	config, err := generator.Generate(kapi.NewDefaultContext(), "deploy1", true)
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.LatestVersion != 2 {
+		t.Fatalf("Expected config LatestVersion=2, got %d", config.LatestVersion)
+	}
+
+	expected := "registry:8080/repo1:ref1"
+	actual := config.Template.ControllerTemplate.Template.Spec.Containers[0].Image
+	if expected != actual {
+		t.Fatalf("Expected container image %s, got %s", expected, actual)
+	}
+}
+
+func TestGenerateForceWithUnresolvableTriggerErrors(t *testing.T) {
+	generator := &DeploymentConfigGenerator{
+		Codec: api.Codec,
+		Client: Client{
+			DCFn: func(ctx kapi.Context, id string) (*deployapi.DeploymentConfig, error) {
+				return deploytest.OkDeploymentConfig(1), nil
+			},
+			LISFn: func(ctx kapi.Context) (*imageapi.ImageStreamList, error) {
+				return &imageapi.ImageStreamList{}, nil
+			},
+		},
+	}
+
+	config, err := generator.Generate(kapi.NewDefaultContext(), "deploy1", true)
+
+	if err == nil {
+		t.Fatalf("Expected an error, got config %#v", config)
+	}
+}
+
+func TestGenerateNonForceBehaviorUnchanged(t *testing.T) {
+	generator := &DeploymentConfigGenerator{
+		Codec: api.Codec,
+		Client: Client{
+			DCFn: func(ctx kapi.Context, id string) (*deployapi.DeploymentConfig, error) {
+				return deploytest.OkDeploymentConfig(1), nil
+			},
+			LISFn: func(ctx kapi.Context) (*imageapi.ImageStreamList, error) {
+				return okImageStreamList(), nil
+			},
+		},
+	}
+
+	config, err := generator.Generate(kapi.NewDefaultContext(), "deploy1", false)
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.LatestVersion != 1 {
+		t.Fatalf("Expected config LatestVersion=1, got %d", config.LatestVersion)
+	}
+}
+
+func configChangeDeploymentConfig() *deployapi.DeploymentConfig {
+	dc := basicDeploymentConfig()
+	dc.Triggers = []deployapi.DeploymentTriggerPolicy{
+		{Type: deployapi.DeploymentTriggerOnConfigChange},
+	}
+	return dc
+}
+
+func deploymentFor(config *deployapi.DeploymentConfig) *kapi.ReplicationController {
+	encodedConfig, _ := deployutil.EncodeDeploymentConfig(config, api.Codec)
+
+	return &kapi.ReplicationController{
+		ObjectMeta: kapi.ObjectMeta{
+			Name: deployutil.LatestDeploymentNameForConfig(config),
+			Annotations: map[string]string{
+				deployapi.DeploymentConfigAnnotation:        config.Name,
+				deployapi.DeploymentStatusAnnotation:        string(deployapi.DeploymentStatusNew),
+				deployapi.DeploymentEncodedConfigAnnotation: encodedConfig,
+			},
+			Labels: config.Labels,
+		},
+		Spec: kapi.ReplicationControllerSpec{
+			Template: config.Template.ControllerTemplate.Template,
+		},
+	}
+}
+
+func TestGenerateRollbackMissingSourceDeployment(t *testing.T) {
+	generator := &DeploymentConfigGenerator{
+		Codec: api.Codec,
+		Client: Client{
+			RCFn: func(ctx kapi.Context, name string) (*kapi.ReplicationController, error) {
+				return nil, kerrors.NewNotFound("replicationController", name)
+			},
+		},
+	}
+
+	config, err := generator.GenerateRollback(kapi.NewDefaultContext(), "deploy1-1", basicDeploymentConfig(), &deployapi.DeploymentConfigRollbackSpec{})
+
+	if config != nil {
+		t.Fatalf("Unexpected rollback config generated: %#v", config)
+	}
+	if err == nil {
+		t.Fatalf("Expected an error")
+	}
+}
+
+func TestGenerateRollbackDecodeFailure(t *testing.T) {
+	generator := &DeploymentConfigGenerator{
+		Codec: api.Codec,
+		Client: Client{
+			RCFn: func(ctx kapi.Context, name string) (*kapi.ReplicationController, error) {
+				return &kapi.ReplicationController{
+					ObjectMeta: kapi.ObjectMeta{Name: name},
+				}, nil
+			},
+		},
+	}
+
+	config, err := generator.GenerateRollback(kapi.NewDefaultContext(), "deploy1-1", basicDeploymentConfig(), &deployapi.DeploymentConfigRollbackSpec{})
+
+	if config != nil {
+		t.Fatalf("Unexpected rollback config generated: %#v", config)
+	}
+	if err == nil {
+		t.Fatalf("Expected a decode error")
+	}
+}
+
+func TestGenerateRollbackCallsGRFnWithDecodedSource(t *testing.T) {
+	fromConfig := basicDeploymentConfig()
+	toConfig := basicDeploymentConfig()
+	spec := &deployapi.DeploymentConfigRollbackSpec{IncludeTriggers: true}
+	sentinel := basicDeploymentConfig()
+	sentinel.Name = "sentinel"
+
+	var gotFrom, gotTo *deployapi.DeploymentConfig
+	var gotSpec *deployapi.DeploymentConfigRollbackSpec
+
+	generator := &DeploymentConfigGenerator{
+		Codec: api.Codec,
+		Client: Client{
+			RCFn: func(ctx kapi.Context, name string) (*kapi.ReplicationController, error) {
+				if name != "deploy1-1" {
+					t.Errorf("unexpected RC name %s", name)
+				}
+				return deploymentFor(fromConfig), nil
+			},
+			GRFn: func(from, to *deployapi.DeploymentConfig, s *deployapi.DeploymentConfigRollbackSpec) (*deployapi.DeploymentConfig, error) {
+				gotFrom = from
+				gotTo = to
+				gotSpec = s
+				return sentinel, nil
+			},
+		},
+	}
+
+	result, err := generator.GenerateRollback(kapi.NewDefaultContext(), "deploy1-1", toConfig, spec)
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != sentinel {
+		t.Fatalf("Expected GRFn's result to be returned unmodified, got %#v", result)
+	}
+	if gotFrom == nil || gotFrom.Name != fromConfig.Name {
+		t.Fatalf("Expected the decoded source config %q to be passed to GRFn, got %#v", fromConfig.Name, gotFrom)
+	}
+	if gotTo != toConfig {
+		t.Fatalf("Expected to to be passed to GRFn unmodified")
+	}
+	if gotSpec != spec {
+		t.Fatalf("Expected spec to be passed to GRFn unmodified")
+	}
+}
+
+func TestGenerateRollbackMergesOnlyEnabledFields(t *testing.T) {
+	from := basicDeploymentConfig()
+	from.Triggers = []deployapi.DeploymentTriggerPolicy{
+		{Type: deployapi.DeploymentTriggerManual},
+	}
+	from.Template.ControllerTemplate.Replicas = 5
+	from.Template.Strategy = deployapi.DeploymentStrategy{Type: deployapi.DeploymentStrategyTypeRecreate}
+	from.Template.ControllerTemplate.Template.Spec.Containers[0].Image = "registry:8080/repo1:rollback"
+
+	to := basicDeploymentConfig()
+	to.LatestVersion = 2
+
+	tests := []struct {
+		name string
+		spec *deployapi.DeploymentConfigRollbackSpec
+		test func(*deployapi.DeploymentConfig) error
+	}{
+		{
+			name: "triggers",
+			spec: &deployapi.DeploymentConfigRollbackSpec{IncludeTriggers: true},
+			test: func(result *deployapi.DeploymentConfig) error {
+				if len(result.Triggers) != 1 || result.Triggers[0].Type != deployapi.DeploymentTriggerManual {
+					return fmt.Errorf("expected triggers to be rolled back, got %#v", result.Triggers)
+				}
+				if result.Template.ControllerTemplate.Replicas != 0 {
+					return fmt.Errorf("expected replicas to be left alone, got %d", result.Template.ControllerTemplate.Replicas)
+				}
+				return nil
+			},
+		},
+		{
+			name: "replicationMeta",
+			spec: &deployapi.DeploymentConfigRollbackSpec{IncludeReplicationMeta: true},
+			test: func(result *deployapi.DeploymentConfig) error {
+				if result.Template.ControllerTemplate.Replicas != 5 {
+					return fmt.Errorf("expected replicas to be rolled back, got %d", result.Template.ControllerTemplate.Replicas)
+				}
+				if len(result.Triggers) != len(to.Triggers) {
+					return fmt.Errorf("expected triggers to be left alone, got %#v", result.Triggers)
+				}
+				return nil
+			},
+		},
+		{
+			name: "strategy",
+			spec: &deployapi.DeploymentConfigRollbackSpec{IncludeStrategy: true},
+			test: func(result *deployapi.DeploymentConfig) error {
+				if result.Template.Strategy.Type != deployapi.DeploymentStrategyTypeRecreate {
+					return fmt.Errorf("expected strategy to be rolled back, got %#v", result.Template.Strategy)
+				}
+				return nil
+			},
+		},
+		{
+			name: "template",
+			spec: &deployapi.DeploymentConfigRollbackSpec{IncludeTemplate: true},
+			test: func(result *deployapi.DeploymentConfig) error {
+				image := result.Template.ControllerTemplate.Template.Spec.Containers[0].Image
+				if image != "registry:8080/repo1:rollback" {
+					return fmt.Errorf("expected pod template to be rolled back, got image %s", image)
+				}
+				if result.Template.ControllerTemplate.Replicas != 0 {
+					return fmt.Errorf("expected replicas to be left alone, got %d", result.Template.ControllerTemplate.Replicas)
+				}
+				if len(result.Triggers) != len(to.Triggers) {
+					return fmt.Errorf("expected triggers to be left alone, got %#v", result.Triggers)
+				}
+				return nil
+			},
+		},
+	}
+
+	rcGenerator := &DeploymentConfigRollbackGenerator{}
+	for _, test := range tests {
+		result, err := rcGenerator.GenerateRollback(from, to, test.spec)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if result.LatestVersion != to.LatestVersion+1 {
+			t.Errorf("%s: expected LatestVersion %d, got %d", test.name, to.LatestVersion+1, result.LatestVersion)
+		}
+		if err := test.test(result); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+	}
+}
+
+func emptyImageStream() *imageapi.ImageStreamList {
+	return &imageapi.ImageStreamList{
+		Items: []imageapi.ImageStream{
 			{
 				ObjectMeta: kapi.ObjectMeta{Name: "imageRepo1"},
-				Tags: map[string]string{
-					"tag1": "ref1",
-				},
-				Status: imageapi.ImageRepositoryStatus{
+				Status: imageapi.ImageStreamStatus{
 					DockerImageRepository: "",
 				},
 			},